@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+)
+
+// interactiveLoginMarker is the substring Login's error carries when a
+// tenant requires interactive SSO/MFA (see auth_test.go). Login reports the
+// condition as plain text rather than a sentinel error, so
+// IsInteractiveLoginRequired matches on it the same way the existing tests
+// do.
+const interactiveLoginMarker = "interactive SSO/MFA"
+
+// IsInteractiveLoginRequired reports whether err is the "session not
+// established" condition Login returns for tenants that require
+// interactive SSO/MFA, the trigger for falling back to LoginInteractive.
+func IsInteractiveLoginRequired(err error) bool {
+	return err != nil && strings.Contains(err.Error(), interactiveLoginMarker)
+}
+
+// Session is the subset of an authenticated cookie jar that's worth
+// persisting across CLI invocations: just enough to repopulate a
+// http.CookieJar without storing the password that produced it.
+type Session struct {
+	BaseURL string          `json:"base_url"`
+	Cookies []SessionCookie `json:"cookies"`
+}
+
+// SessionCookie mirrors the fields of http.Cookie that matter for
+// replaying a session: name, value, and the scope it was issued for.
+type SessionCookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Path   string `json:"path,omitempty"`
+	Domain string `json:"domain,omitempty"`
+}
+
+// LoginInteractive recovers from a Login call that failed because the
+// tenant requires interactive SSO/MFA. It opens BaseURL + "/login.html" in
+// the user's default browser, waits on a loopback HTTP listener for the
+// post-login session cookies, loads them into Client.Jar, validates the
+// resulting session the same way Login does, then persists it via
+// keyring.SaveSessionForProfile under preferredStore (the same
+// --credential-store selection Login's caller would have used) so later
+// commands can resume without re-prompting.
+func (a *Authenticator) LoginInteractive(ctx context.Context, profile string, preferredStore string) error {
+	loginURL := strings.TrimRight(a.BaseURL, "/") + "/login.html"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("start loopback listener: %w", err)
+	}
+	defer listener.Close()
+
+	instructionsURL := fmt.Sprintf("http://%s/", listener.Addr().String())
+	token, err := generateCallbackToken()
+	if err != nil {
+		return err
+	}
+	cookiesCh := make(chan []*http.Cookie, 1)
+	errCh := make(chan error, 1)
+
+	srv := &http.Server{Handler: interactiveCallbackHandler(cookiesCh, errCh, token)}
+	go func() {
+		if serveErr := srv.Serve(listener); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			select {
+			case errCh <- serveErr:
+			default:
+			}
+		}
+	}()
+	defer srv.Close()
+
+	fmt.Fprintf(os.Stderr, "Opening %s in your browser to complete SSO/MFA login.\nOnce logged in, open %s for the two copy-paste commands that finish the login.\n", loginURL, instructionsURL)
+
+	if err := openBrowser(loginURL); err != nil {
+		fmt.Fprintf(os.Stderr, "could not open browser automatically: %v\nVisit %s manually instead.\n", err, loginURL)
+	}
+
+	var cookies []*http.Cookie
+	select {
+	case cookies = <-cookiesCh:
+	case err := <-errCh:
+		return fmt.Errorf("interactive login callback failed: %w", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := a.applyInteractiveCookies(cookies); err != nil {
+		return err
+	}
+
+	if _, err := ExtractAccessToken(a.Client, a.BaseURL); err != nil {
+		return fmt.Errorf("interactive login did not produce a valid session: %w", err)
+	}
+	if _, err := ExtractXSRFToken(a.Client, a.BaseURL); err != nil {
+		return fmt.Errorf("interactive login did not produce a valid session: %w", err)
+	}
+
+	return a.saveSession(profile, cookies, preferredStore)
+}
+
+// ResumeSession loads a previously persisted interactive session for
+// profile and installs it into Client.Jar, validating it the same way
+// LoginInteractive does. Callers should fall back to Login or
+// LoginInteractive when it returns an error.
+func (a *Authenticator) ResumeSession(profile string) error {
+	data, err := keyring.LoadSessionForProfile(profile, "")
+	if err != nil {
+		return fmt.Errorf("load session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return fmt.Errorf("parse stored session: %w", err)
+	}
+
+	if a.Client.Jar == nil {
+		return errors.New("authenticator has no cookie jar configured")
+	}
+	u, err := url.Parse(a.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	cookies := make([]*http.Cookie, 0, len(session.Cookies))
+	for _, c := range session.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain})
+	}
+	a.Client.Jar.SetCookies(u, cookies)
+
+	if _, err := ExtractAccessToken(a.Client, a.BaseURL); err != nil {
+		return fmt.Errorf("stored session is no longer valid: %w", err)
+	}
+	if _, err := ExtractXSRFToken(a.Client, a.BaseURL); err != nil {
+		return fmt.Errorf("stored session is no longer valid: %w", err)
+	}
+	return nil
+}
+
+func (a *Authenticator) applyInteractiveCookies(cookies []*http.Cookie) error {
+	if len(cookies) == 0 {
+		return errors.New("no cookies were submitted")
+	}
+	if a.Client.Jar == nil {
+		return errors.New("authenticator has no cookie jar configured")
+	}
+	u, err := url.Parse(a.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	a.Client.Jar.SetCookies(u, cookies)
+	return nil
+}
+
+func (a *Authenticator) saveSession(profile string, cookies []*http.Cookie, preferredStore string) error {
+	session := Session{BaseURL: a.BaseURL, Cookies: make([]SessionCookie, 0, len(cookies))}
+	for _, c := range cookies {
+		session.Cookies = append(session.Cookies, SessionCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain})
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("marshal session: %w", err)
+	}
+	if err := keyring.SaveSessionForProfile(profile, data, preferredStore); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+// interactiveLoginScopes are the two cookie-jar paths ExtractAccessToken
+// and ExtractXSRFToken read from (see auth_test.go). document.cookie only
+// ever reveals cookies visible at the page it's read from, and those two
+// paths never overlap, so a single page submission can't carry both
+// cookies: the instructions page asks for one submission from each scope
+// and interactiveCallbackHandler merges them before releasing cookiesCh.
+var interactiveLoginScopes = map[string]string{
+	"access": "/wand2",
+	"xsrf":   "/wand",
+}
+
+const interactiveLoginPage = `<!DOCTYPE html>
+<html>
+<head><title>magnit-vms-cli interactive login</title></head>
+<body>
+<p>Complete SSO/MFA login in the other tab first. Once you're logged in,
+finish here by running one command per step below (open the browser
+console on each page and paste it in):</p>
+<ol>
+<li>On a page under <code>/wand2</code> (e.g. the tenant's current-user
+API), run:
+<pre>fetch(%q, {method: "POST", body: document.cookie})</pre></li>
+<li>On a page under <code>/wand</code> (e.g. the main application), run:
+<pre>fetch(%q, {method: "POST", body: document.cookie})</pre></li>
+</ol>
+<p>The CLI continues on its own once both steps are received.</p>
+</body>
+</html>`
+
+// generateCallbackToken returns a random hex token embedded in this
+// invocation's instructions-page callback URLs and required on every
+// /callback/* submission, so a page other than the one printed in the
+// instructions can't blind-POST cookies into the session being built (see
+// interactiveCallbackHandler).
+func generateCallbackToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", fmt.Errorf("generate callback token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// interactiveCallbackHandler serves the instructions page and the two
+// cookie-submission endpoints it links to. token must match the
+// "token" query parameter on every /callback/* request; this is the only
+// thing standing between the loopback listener and any other page open in
+// the user's browser during the login window, so requests without it are
+// rejected before their body is even read.
+func interactiveCallbackHandler(cookies chan<- []*http.Cookie, errs chan<- error, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		fmt.Fprintf(w, interactiveLoginPage, base+"/callback/access?token="+token, base+"/callback/xsrf?token="+token)
+	})
+
+	var mu sync.Mutex
+	received := make(map[string][]*http.Cookie, len(interactiveLoginScopes))
+
+	for scope, path := range interactiveLoginScopes {
+		mux.HandleFunc("/callback/"+scope, func(scope, path string) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("token") != token {
+					http.Error(w, "missing or invalid callback token", http.StatusForbidden)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, "failed to read cookies", http.StatusBadRequest)
+					select {
+					case errs <- fmt.Errorf("read %s callback body: %w", scope, err):
+					default:
+					}
+					return
+				}
+				parsed := parseCookieString(strings.TrimSpace(string(body)))
+				if len(parsed) == 0 {
+					http.Error(w, "no cookies found in submission", http.StatusBadRequest)
+					return
+				}
+				for _, c := range parsed {
+					c.Path = path
+				}
+
+				mu.Lock()
+				received[scope] = parsed
+				var merged []*http.Cookie
+				done := len(received) == len(interactiveLoginScopes)
+				if done {
+					for _, cs := range received {
+						merged = append(merged, cs...)
+					}
+				}
+				mu.Unlock()
+
+				w.WriteHeader(http.StatusOK)
+				if done {
+					select {
+					case cookies <- merged:
+					default:
+					}
+				}
+			}
+		}(scope, path))
+	}
+	return mux
+}
+
+// parseCookieString parses a "document.cookie"-style "name=value; ..." pair
+// list into individual cookies by borrowing http.Request's Cookie header
+// parser, the same format a captured-cookies bookmarklet would submit.
+func parseCookieString(raw string) []*http.Cookie {
+	header := http.Header{}
+	header.Set("Cookie", raw)
+	req := &http.Request{Header: header}
+	return req.Cookies()
+}
+
+// openBrowser launches the platform default browser at target. It is a
+// package-level var so tests can stub it out without spawning a real
+// browser process.
+var openBrowser = func(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsInteractiveLoginRequired(t *testing.T) {
+	if IsInteractiveLoginRequired(nil) {
+		t.Fatal("expected nil error to not require interactive login")
+	}
+	if IsInteractiveLoginRequired(errors.New("invalid username or password")) {
+		t.Fatal("expected invalid credentials error to not require interactive login")
+	}
+	if !IsInteractiveLoginRequired(errors.New("session not established: interactive SSO/MFA required")) {
+		t.Fatal("expected interactive SSO/MFA error to require interactive login")
+	}
+}
+
+func TestParseCookieString(t *testing.T) {
+	cookies := parseCookieString(`productionaccess_token=abc123; X-XSRF-TOKEN="tok%2Ben"`)
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 cookies, got %d", len(cookies))
+	}
+	byName := map[string]string{}
+	for _, c := range cookies {
+		byName[c.Name] = c.Value
+	}
+	if byName["productionaccess_token"] != "abc123" {
+		t.Fatalf("unexpected access token cookie: %+v", byName)
+	}
+}
+
+func TestInteractiveCallbackHandlerMergesBothScopeSubmissions(t *testing.T) {
+	cookiesCh := make(chan []*http.Cookie, 1)
+	errsCh := make(chan error, 1)
+	srv := httptest.NewServer(interactiveCallbackHandler(cookiesCh, errsCh, "test-token"))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/callback/access?token=test-token", "text/plain", strings.NewReader("productionaccess_token=abc123"))
+	if err != nil {
+		t.Fatalf("post access callback: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	select {
+	case cookies := <-cookiesCh:
+		t.Fatalf("expected no cookies delivered until both scopes submit, got %+v", cookies)
+	default:
+	}
+
+	resp, err = http.Post(srv.URL+"/callback/xsrf?token=test-token", "text/plain", strings.NewReader(`X-XSRF-TOKEN="tok%2Ben"`))
+	if err != nil {
+		t.Fatalf("post xsrf callback: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	select {
+	case cookies := <-cookiesCh:
+		byName := map[string]*http.Cookie{}
+		for _, c := range cookies {
+			byName[c.Name] = c
+		}
+		if byName["productionaccess_token"] == nil || byName["productionaccess_token"].Path != "/wand2" {
+			t.Fatalf("expected access token cookie scoped to /wand2, got %+v", cookies)
+		}
+		if byName["X-XSRF-TOKEN"] == nil || byName["X-XSRF-TOKEN"].Path != "/wand" {
+			t.Fatalf("expected XSRF token cookie scoped to /wand, got %+v", cookies)
+		}
+	default:
+		t.Fatal("expected merged cookies to be delivered once both scopes submitted")
+	}
+}
+
+func TestInteractiveCallbackHandlerRejectsEmptySubmission(t *testing.T) {
+	cookiesCh := make(chan []*http.Cookie, 1)
+	errsCh := make(chan error, 1)
+	srv := httptest.NewServer(interactiveCallbackHandler(cookiesCh, errsCh, "test-token"))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/callback/access?token=test-token", "text/plain", strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("post callback: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestInteractiveCallbackHandlerRejectsMissingOrWrongToken(t *testing.T) {
+	cookiesCh := make(chan []*http.Cookie, 1)
+	errsCh := make(chan error, 1)
+	srv := httptest.NewServer(interactiveCallbackHandler(cookiesCh, errsCh, "correct-token"))
+	defer srv.Close()
+
+	cases := []string{
+		srv.URL + "/callback/access",
+		srv.URL + "/callback/access?token=wrong-token",
+	}
+	for _, url := range cases {
+		resp, err := http.Post(url, "text/plain", strings.NewReader("productionaccess_token=abc123"))
+		if err != nil {
+			t.Fatalf("post callback: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for %s, got %d", url, resp.StatusCode)
+		}
+	}
+
+	select {
+	case cookies := <-cookiesCh:
+		t.Fatalf("expected no cookies delivered for unauthenticated submissions, got %+v", cookies)
+	default:
+	}
+}
+
+func TestInteractiveLoginPageEmbedsCallbackToken(t *testing.T) {
+	cookiesCh := make(chan []*http.Cookie, 1)
+	errsCh := make(chan error, 1)
+	srv := httptest.NewServer(interactiveCallbackHandler(cookiesCh, errsCh, "page-token"))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("get instructions page: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read instructions page: %v", err)
+	}
+	if !strings.Contains(string(body), "token=page-token") {
+		t.Fatalf("expected instructions page to embed callback token, got %s", body)
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"flag"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+)
+
+// LoginCommand implements `magnit-vms login`: it authenticates against
+// authn's configured tenant and, on success, persists the credentials (or,
+// for an SSO/MFA tenant reached via --sso, the interactive session) under
+// the resolved profile so later commands can reuse them without
+// re-prompting.
+func LoginCommand(ctx context.Context, app *App, authn *auth.Authenticator, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	username := fs.String("username", "", "account username")
+	password := fs.String("password", "", "account password")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin")
+	profileFlag := fs.String("profile", "", "named credential profile to save the credentials or session under (MAGNIT_PROFILE overrides this)")
+	store := fs.String("credential-store", "", "credential store backend (auto, keyring, file, file-encrypted, helper[:name])")
+	sso := fs.Bool("sso", false, "fall back to an interactive browser-based SSO/MFA login when form login requires it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	passwordFlagSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "password" {
+			passwordFlagSet = true
+		}
+	})
+
+	pass, err := resolvePassword(app, *password, passwordFlagSet, *passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	profile := resolveProfileFlag(*profileFlag)
+
+	loginErr := authn.Login(ctx, *username, pass)
+	if *sso && auth.IsInteractiveLoginRequired(loginErr) {
+		return ssoFallbackLogin(ctx, authn, profile, loginErr, *store)
+	}
+	if loginErr != nil {
+		return loginErr
+	}
+
+	return keyring.SaveCredentialsForProfile(profile, keyring.Credentials{
+		Username:  *username,
+		Password:  pass,
+		ServerURL: authn.BaseURL,
+	}, *store)
+}
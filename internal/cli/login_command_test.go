@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+)
+
+func isolateConfigHome(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+}
+
+// TestLoginCommandSSOWiresProfileAndStore drives LoginCommand end to end
+// against a tenant that requires interactive SSO/MFA, proving that --sso
+// actually reaches ssoFallbackLogin/LoginInteractive and that --profile and
+// --credential-store are threaded all the way through to the saved
+// session, not just exercised on the leaf helpers in isolation.
+func TestLoginCommandSSOWiresProfileAndStore(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(keyring.CredentialStoreEnvVar, "")
+	t.Setenv(keyring.ProfileEnvVar, "")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login.html":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`
+				<html><body>
+				<span>Please log in to your account below</span>
+				<form><input name="password_login" /></form>
+				</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("create cookie jar: %v", err)
+	}
+	authn := &auth.Authenticator{BaseURL: srv.URL, Client: &http.Client{Transport: srv.Client().Transport, Jar: jar}}
+
+	origStderr := os.Stderr
+	pipeR, pipeW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create stderr pipe: %v", err)
+	}
+	os.Stderr = pipeW
+	t.Cleanup(func() { os.Stderr = origStderr })
+
+	instructionsLineRe := regexp.MustCompile(`open (\S+) for`)
+	instructionsCh := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(pipeR)
+		for scanner.Scan() {
+			if m := instructionsLineRe.FindStringSubmatch(scanner.Text()); m != nil {
+				instructionsCh <- m[1]
+				return
+			}
+		}
+	}()
+
+	resultCh := make(chan error, 1)
+	go func() {
+		app := &App{}
+		resultCh <- LoginCommand(context.Background(), app, authn, []string{
+			"--username", "user@example.com",
+			"--password", "password",
+			"--profile", "staging",
+			"--sso",
+			"--credential-store", "file",
+		})
+	}()
+
+	var instructionsURL string
+	select {
+	case instructionsURL = <-instructionsCh:
+	case err := <-resultCh:
+		t.Fatalf("LoginCommand returned before printing instructions: %v", err)
+	}
+
+	page, err := http.Get(instructionsURL)
+	if err != nil {
+		t.Fatalf("get instructions page: %v", err)
+	}
+	body := make([]byte, 0, 4096)
+	buf := make([]byte, 4096)
+	for {
+		n, readErr := page.Body.Read(buf)
+		body = append(body, buf[:n]...)
+		if readErr != nil {
+			break
+		}
+	}
+	page.Body.Close()
+
+	tokenRe := regexp.MustCompile(`token=([0-9a-f]+)`)
+	m := tokenRe.FindStringSubmatch(string(body))
+	if m == nil {
+		t.Fatalf("expected instructions page to embed a callback token, got %s", body)
+	}
+	token := m[1]
+	base := strings.TrimSuffix(instructionsURL, "/")
+
+	postCookie := func(path, cookie string) {
+		t.Helper()
+		resp, err := http.Post(base+path+"?token="+token, "text/plain", strings.NewReader(cookie))
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("post %s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+	postCookie("/callback/access", "productionaccess_token=abc123")
+	postCookie("/callback/xsrf", `X-XSRF-TOKEN="tok%2Ben"`)
+
+	if err := <-resultCh; err != nil {
+		t.Fatalf("LoginCommand returned error: %v", err)
+	}
+
+	pipeW.Close()
+	os.Stderr = origStderr
+
+	data, err := keyring.LoadSessionForProfile("staging", "file")
+	if err != nil {
+		t.Fatalf("load saved session: %v", err)
+	}
+	if !strings.Contains(string(data), "abc123") {
+		t.Fatalf("expected saved session to contain the submitted cookie, got %s", data)
+	}
+
+	if _, err := keyring.LoadSessionForProfile("", "file"); err != keyring.ErrCredentialsNotFound {
+		t.Fatalf("expected default profile to have no session, got %v", err)
+	}
+}
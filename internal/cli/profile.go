@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+)
+
+// resolveProfileFlag applies the same precedence keyring.resolveProfile
+// uses internally: keyring.ProfileEnvVar (set via MAGNIT_PROFILE) overrides
+// the --profile flag value, so scripting a tenant switch doesn't require
+// threading a flag through every command.
+func resolveProfileFlag(flagProfile string) string {
+	if env := strings.TrimSpace(os.Getenv(keyring.ProfileEnvVar)); env != "" {
+		return env
+	}
+	return strings.TrimSpace(flagProfile)
+}
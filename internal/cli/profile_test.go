@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/ihildy/magnit-vms-cli/internal/keyring"
+)
+
+func TestResolveProfileFlagPrefersExplicitFlagOverDefault(t *testing.T) {
+	t.Setenv(keyring.ProfileEnvVar, "")
+
+	if got := resolveProfileFlag("staging"); got != "staging" {
+		t.Fatalf("resolveProfileFlag(%q) = %q, want %q", "staging", got, "staging")
+	}
+}
+
+func TestResolveProfileFlagEnvVarOverridesFlag(t *testing.T) {
+	t.Setenv(keyring.ProfileEnvVar, "fromenv")
+
+	if got := resolveProfileFlag("staging"); got != "fromenv" {
+		t.Fatalf("resolveProfileFlag(%q) = %q, want %q", "staging", got, "fromenv")
+	}
+}
+
+func TestResolveProfileFlagEmpty(t *testing.T) {
+	t.Setenv(keyring.ProfileEnvVar, "")
+
+	if got := resolveProfileFlag(""); got != "" {
+		t.Fatalf("resolveProfileFlag(\"\") = %q, want empty", got)
+	}
+}
@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ihildy/magnit-vms-cli/internal/auth"
+)
+
+// ssoFallbackLogin retries a failed form login through the interactive
+// SSO/MFA browser flow when loginErr is the "interactive SSO/MFA" condition
+// Authenticator.Login reports. The login command calls this when --sso was
+// passed instead of surfacing the original error directly. preferredStore
+// is forwarded to LoginInteractive so the resulting session is saved under
+// the same --credential-store the caller asked for.
+func ssoFallbackLogin(ctx context.Context, authn *auth.Authenticator, profile string, loginErr error, preferredStore string) error {
+	if !auth.IsInteractiveLoginRequired(loginErr) {
+		return loginErr
+	}
+	return authn.LoginInteractive(ctx, profile, preferredStore)
+}
@@ -0,0 +1,16 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSsoFallbackLoginReturnsOriginalErrorWhenNotInteractive(t *testing.T) {
+	loginErr := errors.New("invalid username or password")
+
+	err := ssoFallbackLogin(context.Background(), nil, "default", loginErr, "")
+	if !errors.Is(err, loginErr) {
+		t.Fatalf("expected original error to be returned unchanged, got %v", err)
+	}
+}
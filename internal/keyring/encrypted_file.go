@@ -0,0 +1,310 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// StoreFileEncrypted selects the same credentials.yaml location as
+	// StoreFile, but encrypted at rest. See encryptDocument/decryptDocument
+	// for the on-disk framing.
+	StoreFileEncrypted = "file-encrypted"
+
+	// PassphraseEnvVar supplies the passphrase used to derive the
+	// encryption key via scrypt, skipping the interactive prompt.
+	PassphraseEnvVar = "MAGNIT_CREDENTIAL_PASSPHRASE"
+	// AgeRecipientEnvVar supplies an age X25519 recipient (public key) to
+	// wrap the data-encryption key for on save, instead of a passphrase.
+	AgeRecipientEnvVar = "MAGNIT_CREDENTIAL_AGE_RECIPIENT"
+	// AgeIdentityEnvVar supplies an age X25519 identity (private key) used
+	// to unwrap a recipient-encrypted key on load.
+	AgeIdentityEnvVar = "MAGNIT_CREDENTIAL_AGE_IDENTITY"
+
+	encryptedKDFScrypt byte = 1
+	encryptedKDFAge    byte = 2
+
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	scryptKeyLen  = 32
+	scryptSaltLen = 16
+)
+
+var encryptedFileMagic = [5]byte{'M', 'V', 'M', 'S', 0x01}
+
+// passphrasePrompt reads a passphrase from the terminal. It is a package
+// level var so tests can stub it the same way interactive auth stubs
+// openBrowser.
+var passphrasePrompt = func(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("read passphrase: %w", err)
+		}
+		return string(passphrase), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// encryptionConfigured reports whether enough material is present to
+// encrypt a newly written credentials.yaml without prompting: either an
+// age recipient or a passphrase is already available via environment
+// variable. It is used by StoreAuto to prefer encrypted-file over
+// plaintext-file when the OS keyring is unavailable.
+func encryptionConfigured() bool {
+	if strings.TrimSpace(os.Getenv(AgeRecipientEnvVar)) != "" {
+		return true
+	}
+	return strings.TrimSpace(os.Getenv(PassphraseEnvVar)) != ""
+}
+
+// isEncryptedDocument reports whether data begins with the credentials.yaml
+// encrypted-file magic bytes.
+func isEncryptedDocument(data []byte) bool {
+	return len(data) >= len(encryptedFileMagic) && bytes.Equal(data[:len(encryptedFileMagic)], encryptedFileMagic[:])
+}
+
+// encryptDocument marshals doc as YAML and encrypts it with
+// XChaCha20-Poly1305 under a random 24-byte nonce. The data-encryption key
+// is derived from a passphrase via scrypt, or wrapped for an age X25519
+// recipient when AgeRecipientEnvVar is set; age takes precedence since it
+// avoids a per-use passphrase prompt. The on-disk framing is:
+//
+//	magic(5) | kdfID(1) | keyMaterialLen(uint16 BE) | keyMaterial | nonce(24) | ciphertext
+//
+// where keyMaterial is the scrypt salt (16 bytes) or an age-wrapped key
+// blob, depending on kdfID.
+func encryptDocument(doc credentialsDocument) ([]byte, error) {
+	plaintext, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal credentials: %w", err)
+	}
+	return encryptPlaintext(plaintext)
+}
+
+// encryptPlaintext encrypts an arbitrary plaintext blob under the same
+// framing as encryptDocument, for callers (e.g. the session store) whose
+// payload isn't a credentialsDocument.
+func encryptPlaintext(plaintext []byte) ([]byte, error) {
+	key := make([]byte, scryptKeyLen)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("generate encryption key: %w", err)
+	}
+
+	var kdfID byte
+	var keyMaterial []byte
+	if recipient := strings.TrimSpace(os.Getenv(AgeRecipientEnvVar)); recipient != "" {
+		wrapped, err := wrapKeyForAgeRecipient(recipient, key)
+		if err != nil {
+			return nil, err
+		}
+		kdfID = encryptedKDFAge
+		keyMaterial = wrapped
+	} else {
+		salt := make([]byte, scryptSaltLen)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, fmt.Errorf("generate scrypt salt: %w", err)
+		}
+		passphrase, err := resolvePassphrase("Credential store passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		derived, err := deriveKeyFromPassphrase(passphrase, salt)
+		if err != nil {
+			return nil, err
+		}
+		copy(key, derived)
+		kdfID = encryptedKDFScrypt
+		keyMaterial = salt
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
+
+	var out bytes.Buffer
+	out.Write(encryptedFileMagic[:])
+	out.WriteByte(kdfID)
+	var keyMaterialLen [2]byte
+	binary.BigEndian.PutUint16(keyMaterialLen[:], uint16(len(keyMaterial)))
+	out.Write(keyMaterialLen[:])
+	out.Write(keyMaterial)
+	out.Write(nonce)
+	out.Write(ciphertext)
+	return out.Bytes(), nil
+}
+
+// decryptDocument reverses encryptDocument, deriving or unwrapping the
+// same key from the framed file and parsing the resulting YAML.
+func decryptDocument(data []byte) (credentialsDocument, error) {
+	plaintext, err := decryptPlaintext(data)
+	if err != nil {
+		return credentialsDocument{}, err
+	}
+	var doc credentialsDocument
+	if err := yaml.Unmarshal(plaintext, &doc); err != nil {
+		return credentialsDocument{}, fmt.Errorf("parse decrypted credentials file: %w", err)
+	}
+	return doc, nil
+}
+
+// decryptPlaintext reverses encryptPlaintext, deriving or unwrapping the
+// same key from the framed blob and returning the raw plaintext.
+func decryptPlaintext(data []byte) ([]byte, error) {
+	if !isEncryptedDocument(data) {
+		return nil, errors.New("data is not encrypted")
+	}
+	rest := data[len(encryptedFileMagic):]
+	if len(rest) < 3 {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	kdfID := rest[0]
+	keyMaterialLen := binary.BigEndian.Uint16(rest[1:3])
+	rest = rest[3:]
+	if len(rest) < int(keyMaterialLen)+chacha20poly1305.NonceSizeX {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	keyMaterial := rest[:keyMaterialLen]
+	rest = rest[keyMaterialLen:]
+	nonce := rest[:chacha20poly1305.NonceSizeX]
+	ciphertext := rest[chacha20poly1305.NonceSizeX:]
+
+	var key []byte
+	switch kdfID {
+	case encryptedKDFScrypt:
+		passphrase, err := resolvePassphrase("Credential store passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		derived, err := deriveKeyFromPassphrase(passphrase, keyMaterial)
+		if err != nil {
+			return nil, err
+		}
+		key = derived
+	case encryptedKDFAge:
+		unwrapped, err := unwrapKeyWithAgeIdentity(keyMaterial)
+		if err != nil {
+			return nil, err
+		}
+		key = unwrapped
+	default:
+		return nil, fmt.Errorf("unsupported encryption kdf id %d", kdfID)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data: %w", err)
+	}
+	return plaintext, nil
+}
+
+func resolvePassphrase(prompt string) (string, error) {
+	if value := os.Getenv(PassphraseEnvVar); value != "" {
+		return value, nil
+	}
+	return passphrasePrompt(prompt)
+}
+
+func deriveKeyFromPassphrase(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("derive key from passphrase: %w", err)
+	}
+	return key, nil
+}
+
+func wrapKeyForAgeRecipient(recipientStr string, key []byte) ([]byte, error) {
+	recipient, err := age.ParseX25519Recipient(recipientStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", AgeRecipientEnvVar, err)
+	}
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("wrap key for age recipient: %w", err)
+	}
+	if _, err := w.Write(key); err != nil {
+		return nil, fmt.Errorf("wrap key for age recipient: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("wrap key for age recipient: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func unwrapKeyWithAgeIdentity(wrapped []byte) ([]byte, error) {
+	identityStr := strings.TrimSpace(os.Getenv(AgeIdentityEnvVar))
+	if identityStr == "" {
+		return nil, fmt.Errorf("credentials file is age-encrypted; set %s to decrypt it", AgeIdentityEnvVar)
+	}
+	identity, err := age.ParseX25519Identity(identityStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", AgeIdentityEnvVar, err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(wrapped), identity)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap key with age identity: %w", err)
+	}
+	key, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap key with age identity: %w", err)
+	}
+	return key, nil
+}
+
+// zeroPlaintextCredentialsFile overwrites an existing plaintext
+// credentials.yaml with zero bytes. saveToFile calls this immediately
+// before its own writeDocument call when migrating a profile to
+// StoreFileEncrypted, so the old plaintext doesn't linger in a
+// filesystem journal or undelete-recoverable block once the encrypted
+// version lands — without a separate encrypt pass of its own, which
+// would otherwise force the caller through an extra decrypt/re-encrypt
+// round trip (and passphrase prompt) for a single save.
+func zeroPlaintextCredentialsFile() error {
+	path, err := credentialsFilePath()
+	if err != nil {
+		return fmt.Errorf("resolve credentials path: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read credentials file: %w", err)
+	}
+	zeroed := make([]byte, len(data))
+	if err := os.WriteFile(path, zeroed, 0o600); err != nil {
+		return fmt.Errorf("zero plaintext credentials file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+)
+
+func stubPassphrasePrompt(t *testing.T, passphrase string) {
+	t.Helper()
+	original := passphrasePrompt
+	passphrasePrompt = func(string) (string, error) { return passphrase, nil }
+	t.Cleanup(func() { passphrasePrompt = original })
+}
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "")
+	t.Setenv(AgeRecipientEnvVar, "")
+	stubPassphrasePrompt(t, "correct horse battery staple")
+
+	want := Credentials{Username: "user@example.com", Password: "secret"}
+	if err := SaveCredentialsWithStore(want, StoreFileEncrypted); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		t.Fatalf("config path: %v", err)
+	}
+	credPath := filepath.Join(filepath.Dir(cfgPath), credentialsFileName)
+	data, err := os.ReadFile(credPath)
+	if err != nil {
+		t.Fatalf("read credentials file: %v", err)
+	}
+	if !isEncryptedDocument(data) {
+		t.Fatalf("expected credentials file to be encrypted, got %q", data)
+	}
+
+	got, err := LoadCredentialsWithStore(StoreFileEncrypted)
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+	if got != want {
+		t.Fatalf("unexpected credentials: got=%+v want=%+v", got, want)
+	}
+}
+
+func TestEncryptedFileStoreWrongPassphraseFails(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(AgeRecipientEnvVar, "")
+
+	t.Setenv(PassphraseEnvVar, "right-passphrase")
+	want := Credentials{Username: "user@example.com", Password: "secret"}
+	if err := SaveCredentialsWithStore(want, StoreFileEncrypted); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+
+	t.Setenv(PassphraseEnvVar, "wrong-passphrase")
+	if _, err := LoadCredentialsWithStore(StoreFileEncrypted); err == nil {
+		t.Fatal("expected load with wrong passphrase to fail")
+	}
+}
+
+func TestEncryptedFileStoreMigratesPlaintext(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "")
+	t.Setenv(AgeRecipientEnvVar, "")
+
+	plain := Credentials{Username: "plain@example.com", Password: "plain-secret"}
+	if err := SaveCredentialsWithStore(plain, StoreFile); err != nil {
+		t.Fatalf("save plaintext credentials: %v", err)
+	}
+
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		t.Fatalf("config path: %v", err)
+	}
+	credPath := filepath.Join(filepath.Dir(cfgPath), credentialsFileName)
+	before, err := os.ReadFile(credPath)
+	if err != nil {
+		t.Fatalf("read credentials file: %v", err)
+	}
+	if isEncryptedDocument(before) {
+		t.Fatal("expected credentials file to start out plaintext")
+	}
+
+	stubPassphrasePrompt(t, "migration-passphrase")
+	other := Credentials{Username: "other@example.com", Password: "other-secret"}
+	if err := SaveCredentialsForProfile("other", other, StoreFileEncrypted); err != nil {
+		t.Fatalf("save encrypted profile: %v", err)
+	}
+
+	after, err := os.ReadFile(credPath)
+	if err != nil {
+		t.Fatalf("read credentials file: %v", err)
+	}
+	if !isEncryptedDocument(after) {
+		t.Fatal("expected credentials file to be encrypted after migration")
+	}
+
+	got, err := LoadCredentials()
+	if err != nil {
+		t.Fatalf("load migrated default profile: %v", err)
+	}
+	if got != plain {
+		t.Fatalf("expected migrated default profile to survive: got=%+v want=%+v", got, plain)
+	}
+
+	got, err = LoadCredentialsForProfile("other", StoreFileEncrypted)
+	if err != nil {
+		t.Fatalf("load other profile: %v", err)
+	}
+	if got != other {
+		t.Fatalf("unexpected other credentials: got=%+v want=%+v", got, other)
+	}
+}
@@ -0,0 +1,138 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// StoreHelper selects the Docker-style credential helper backend. It may
+	// also be supplied as "helper:<name>" to pin a specific helper binary
+	// without relying on CredentialHelperEnvVar.
+	StoreHelper = "helper"
+	// CredentialHelperEnvVar names the helper binary to shell out to, e.g.
+	// "pass" resolves to the magnit-credential-pass executable on PATH.
+	CredentialHelperEnvVar = "MAGNIT_CREDENTIAL_HELPER"
+
+	helperBinaryPrefix        = "magnit-credential-"
+	helperCredentialsNotFound = "credentials not found"
+)
+
+type helperStorePayload struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+type helperGetResponse struct {
+	Username string `json:"Username"`
+	Secret   string `json:"Secret"`
+}
+
+func isHelperStore(store string) bool {
+	return store == StoreHelper || strings.HasPrefix(store, StoreHelper+":")
+}
+
+// resolveHelperName determines which credential helper binary to invoke,
+// preferring an explicit "helper:<name>" store value over
+// CredentialHelperEnvVar.
+func resolveHelperName(store string) (string, bool) {
+	if name, ok := strings.CutPrefix(store, StoreHelper+":"); ok && name != "" {
+		return name, true
+	}
+	if name := strings.TrimSpace(os.Getenv(CredentialHelperEnvVar)); name != "" {
+		return name, true
+	}
+	return "", false
+}
+
+func runCredentialHelper(name, action string, stdin []byte) ([]byte, error) {
+	bin := helperBinaryPrefix + name
+	cmd := exec.Command(bin, action)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(strings.TrimSpace(stderr.String()), helperCredentialsNotFound) {
+			return nil, ErrCredentialsNotFound
+		}
+		return nil, fmt.Errorf("run credential helper %s %s: %w: %s", bin, action, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// helperServerURL is the identifier sent to the helper for a given profile:
+// creds.ServerURL when the caller supplied one, otherwise serviceName for
+// the default profile or "serviceName/profile" for a named one.
+func helperServerURL(profile, serverURL string) string {
+	if serverURL != "" {
+		return serverURL
+	}
+	if profile == defaultProfileName {
+		return serviceName
+	}
+	return serviceName + "/" + profile
+}
+
+func saveToHelper(name, profile string, creds Credentials) error {
+	serverURL := helperServerURL(profile, creds.ServerURL)
+	payload, err := json.Marshal(helperStorePayload{ServerURL: serverURL, Username: creds.Username, Secret: creds.Password})
+	if err != nil {
+		return fmt.Errorf("marshal helper store payload: %w", err)
+	}
+	if _, err := runCredentialHelper(name, "store", payload); err != nil {
+		return fmt.Errorf("save credentials to helper %q: %w", name, err)
+	}
+	return recordProfileIndex(profile, creds.ServerURL)
+}
+
+func loadFromHelper(name, profile string) (Credentials, error) {
+	recordedURL := profileServerURL(profile)
+	serverURL := helperServerURL(profile, recordedURL)
+	out, err := runCredentialHelper(name, "get", []byte(serverURL))
+	if err != nil {
+		if errors.Is(err, ErrCredentialsNotFound) {
+			return Credentials{}, ErrCredentialsNotFound
+		}
+		return Credentials{}, fmt.Errorf("load credentials from helper %q: %w", name, err)
+	}
+	var resp helperGetResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return Credentials{}, fmt.Errorf("parse helper get response: %w", err)
+	}
+	return Credentials{Username: resp.Username, Password: resp.Secret, ServerURL: recordedURL}, nil
+}
+
+func deleteFromHelper(name, profile string) error {
+	serverURL := helperServerURL(profile, profileServerURL(profile))
+	if _, err := runCredentialHelper(name, "erase", []byte(serverURL)); err != nil {
+		if !errors.Is(err, ErrCredentialsNotFound) {
+			return fmt.Errorf("delete credentials from helper %q: %w", name, err)
+		}
+	}
+	return removeProfileIndex(profile)
+}
+
+// listHelper returns the ServerURL -> Username map reported by the helper's
+// "list" action. It is primarily useful for diagnostics and is not wired
+// into the Save/Load/Delete flow, which addresses one identifier per
+// profile (see helperServerURL).
+func listHelper(name string) (map[string]string, error) {
+	out, err := runCredentialHelper(name, "list", nil)
+	if err != nil {
+		return nil, fmt.Errorf("list credentials from helper %q: %w", name, err)
+	}
+	var list map[string]string
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("parse helper list response: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,112 @@
+package keyring
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeFakeHelper installs a shell script named magnit-credential-<name> on
+// PATH whose body is script, run by /bin/sh with the protocol action as $1.
+// Each test supplies just enough of the Docker credential-helper protocol
+// for what it checks.
+func writeFakeHelper(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake credential helper script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, helperBinaryPrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+		t.Fatalf("write fake helper: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestHelperStoreRoundTrip(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+
+	writeFakeHelper(t, "fake", fmt.Sprintf(`
+case "$1" in
+  store) cat >/dev/null; exit 0 ;;
+  get) cat >/dev/null; printf '{"Username":"user@example.com","Secret":"secret"}'; exit 0 ;;
+  erase) cat >/dev/null; exit 0 ;;
+  *) echo "unsupported action" >&2; exit 1 ;;
+esac
+`))
+
+	want := Credentials{Username: "user@example.com", Password: "secret"}
+	if err := SaveCredentialsWithStore(want, "helper:fake"); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+
+	got, err := LoadCredentialsWithStore("helper:fake")
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+	if got != want {
+		t.Fatalf("unexpected credentials: got=%+v want=%+v", got, want)
+	}
+
+	if err := DeleteCredentialsWithStore("helper:fake"); err != nil {
+		t.Fatalf("delete credentials: %v", err)
+	}
+}
+
+func TestHelperStoreGetNotFound(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+
+	writeFakeHelper(t, "empty", `
+case "$1" in
+  get) cat >/dev/null; echo "credentials not found" >&2; exit 1 ;;
+  *) echo "unsupported action" >&2; exit 1 ;;
+esac
+`)
+
+	_, err := LoadCredentialsWithStore("helper:empty")
+	if err != ErrCredentialsNotFound {
+		t.Fatalf("expected ErrCredentialsNotFound, got %v", err)
+	}
+}
+
+func TestHelperStoreNameRequired(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(CredentialHelperEnvVar, "")
+
+	err := SaveCredentialsWithStore(Credentials{Username: "u", Password: "p"}, StoreHelper)
+	if err == nil {
+		t.Fatal("expected error when no helper name is configured")
+	}
+}
+
+func TestNormalizeStorePreservesHelperNameCase(t *testing.T) {
+	cases := map[string]string{
+		"helper:Pass":     "helper:Pass",
+		"HELPER:Pass":     "helper:Pass",
+		"Helper:MyHelper": "helper:MyHelper",
+		"KEYRING":         "keyring",
+	}
+	for input, want := range cases {
+		if got := normalizeStore(input); got != want {
+			t.Fatalf("normalizeStore(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestValidateCredentialStoreAcceptsHelper(t *testing.T) {
+	valid := []string{StoreHelper, "helper:pass", "HELPER:Pass"}
+	for _, input := range valid {
+		if err := ValidateCredentialStore(input); err != nil {
+			t.Fatalf("expected valid store %q, got error: %v", input, err)
+		}
+	}
+	if err := ValidateCredentialStore("helper:"); err == nil {
+		t.Fatalf("expected validation error for helper store with empty name")
+	}
+}
@@ -0,0 +1,329 @@
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultProfileName is the profile used when the caller does not name
+	// one. It intentionally maps onto the unprefixed keyring keys and the
+	// flat credentials.yaml layout that predate profile support, so existing
+	// single-tenant setups keep working without migration.
+	defaultProfileName = "default"
+	// ProfileEnvVar overrides the profile used by the CLI and keyring
+	// package, taking precedence over an explicit profile argument the same
+	// way CredentialStoreEnvVar overrides preferredStore.
+	ProfileEnvVar = "MAGNIT_PROFILE"
+	// profileIndexFileName holds the always-plaintext record of known
+	// profile names, independent of credentials.yaml. See profileIndexEntry.
+	profileIndexFileName = "profiles.yaml"
+)
+
+// profileEntry is the on-disk representation of a single profile in
+// credentials.yaml, carrying the actual secret. Only the file stores write
+// it; keyring- and helper-backed profiles are recorded in profiles.yaml
+// (see profileIndexEntry) and never in credentials.yaml.
+type profileEntry struct {
+	Username  string `yaml:"username,omitempty"`
+	Password  string `yaml:"password,omitempty"`
+	ServerURL string `yaml:"server_url,omitempty"`
+}
+
+// credentialsDocument is the shape of credentials.yaml: the secret store
+// the file backend reads and writes. It is keyed by profile the same way
+// profileIndexDocument is, but it is NOT the source of truth for which
+// profiles exist — that's profileIndexDocument, which is never encrypted.
+type credentialsDocument struct {
+	Profiles map[string]profileEntry `yaml:"profiles,omitempty"`
+	Default  string                  `yaml:"default,omitempty"`
+}
+
+// profileIndexEntry is the lightweight record kept in profiles.yaml for
+// every known profile, regardless of which store holds its secret: just
+// enough to resolve a default profile, list known profiles, and address a
+// keyring/helper entry by ServerURL. It never holds a secret, so it's
+// always plaintext, and keyring- and helper-backed saves only ever touch
+// this file and never credentials.yaml's (possibly encrypted) contents.
+type profileIndexEntry struct {
+	ServerURL string `yaml:"server_url,omitempty"`
+}
+
+type profileIndexDocument struct {
+	Profiles map[string]profileIndexEntry `yaml:"profiles,omitempty"`
+	Default  string                       `yaml:"default,omitempty"`
+}
+
+// resolveProfile determines which profile a credential operation applies
+// to: ProfileEnvVar wins over an explicit argument, an explicit argument
+// wins over the configured default, and the configured default wins over
+// defaultProfileName.
+func resolveProfile(profile string) (string, error) {
+	value := strings.TrimSpace(os.Getenv(ProfileEnvVar))
+	if value == "" {
+		value = strings.TrimSpace(profile)
+	}
+	if value != "" {
+		return value, nil
+	}
+
+	idx, err := loadProfileIndex()
+	if err != nil {
+		return "", err
+	}
+	if idx.Default != "" {
+		return idx.Default, nil
+	}
+	return defaultProfileName, nil
+}
+
+// ListProfiles returns the names of every profile known to profiles.yaml,
+// regardless of which store actually holds each profile's secret.
+func ListProfiles() ([]string, error) {
+	idx, err := loadProfileIndex()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(idx.Profiles))
+	for name := range idx.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetDefaultProfile marks profile as the one used when no profile is named
+// explicitly. It does not require the profile to already have credentials
+// saved, so it can be set up before the first SaveCredentialsForProfile call.
+func SetDefaultProfile(profile string) error {
+	profile = strings.TrimSpace(profile)
+	if profile == "" {
+		return errors.New("profile is required")
+	}
+
+	idx, err := loadProfileIndex()
+	if err != nil {
+		return err
+	}
+	idx.Default = profile
+	return writeProfileIndex(idx)
+}
+
+// recordProfileIndex registers profile in profiles.yaml. It never touches
+// credentials.yaml, so saving a keyring- or helper-backed profile never
+// forces a decrypt/re-encrypt of an unrelated encrypted file store.
+func recordProfileIndex(profile string, serverURL string) error {
+	idx, err := loadProfileIndex()
+	if err != nil {
+		return err
+	}
+	if idx.Profiles == nil {
+		idx.Profiles = make(map[string]profileIndexEntry)
+	}
+	entry := idx.Profiles[profile]
+	if serverURL != "" {
+		entry.ServerURL = serverURL
+	}
+	idx.Profiles[profile] = entry
+	if idx.Default == "" {
+		idx.Default = profile
+	}
+	return writeProfileIndex(idx)
+}
+
+func removeProfileIndex(profile string) error {
+	idx, err := loadProfileIndex()
+	if err != nil {
+		return err
+	}
+	if _, ok := idx.Profiles[profile]; !ok {
+		return nil
+	}
+	delete(idx.Profiles, profile)
+	if idx.Default == profile {
+		idx.Default = ""
+	}
+	if len(idx.Profiles) == 0 {
+		return removeProfileIndexFile()
+	}
+	return writeProfileIndex(idx)
+}
+
+// ensureProfileIndexed backfills profiles.yaml for a profile discovered in
+// credentials.yaml that predates the index (an install upgraded from a
+// pre-profile-index credentials.yaml). It's a no-op once the profile is
+// already indexed, so a normal load never writes profiles.yaml.
+func ensureProfileIndexed(profile, serverURL string) error {
+	idx, err := loadProfileIndex()
+	if err != nil {
+		return err
+	}
+	if _, ok := idx.Profiles[profile]; ok {
+		return nil
+	}
+	return recordProfileIndex(profile, serverURL)
+}
+
+func profileServerURL(profile string) string {
+	idx, err := loadProfileIndex()
+	if err != nil {
+		return ""
+	}
+	return idx.Profiles[profile].ServerURL
+}
+
+func profileUserKey(profile string) string {
+	if profile == defaultProfileName {
+		return userKey
+	}
+	return profile + "." + userKey
+}
+
+func profilePassKey(profile string) string {
+	if profile == defaultProfileName {
+		return passKey
+	}
+	return profile + "." + passKey
+}
+
+func profileIndexFilePath() (string, error) {
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), profileIndexFileName), nil
+}
+
+func loadProfileIndex() (profileIndexDocument, error) {
+	path, err := profileIndexFilePath()
+	if err != nil {
+		return profileIndexDocument{}, fmt.Errorf("resolve profile index path: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return profileIndexDocument{}, nil
+		}
+		return profileIndexDocument{}, fmt.Errorf("read profile index: %w", err)
+	}
+	var doc profileIndexDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return profileIndexDocument{}, fmt.Errorf("parse profile index: %w", err)
+	}
+	return doc, nil
+}
+
+func writeProfileIndex(doc profileIndexDocument) error {
+	path, err := profileIndexFilePath()
+	if err != nil {
+		return fmt.Errorf("resolve profile index path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	data, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("marshal profile index: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write profile index: %w", err)
+	}
+	return nil
+}
+
+func removeProfileIndexFile() error {
+	path, err := profileIndexFilePath()
+	if err != nil {
+		return fmt.Errorf("resolve profile index path: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("delete profile index: %w", err)
+	}
+	return nil
+}
+
+// saveToFile writes creds for profile into credentials.yaml. encrypt
+// requests encryption for this write; the file is also kept encrypted if
+// it already was, so a plain StoreFile save never silently decrypts a
+// credentials.yaml that StoreFileEncrypted turned into ciphertext earlier.
+// When encrypt newly applies to a credentials.yaml that was plaintext on
+// disk, the old plaintext is zeroed as part of this same load-modify-write
+// pass rather than a separate migration write, so a single save only ever
+// costs one encrypt pass (and one passphrase prompt).
+func saveToFile(profile string, creds Credentials, encrypt bool) error {
+	doc, wasEncrypted, err := loadDocument()
+	if err != nil {
+		return err
+	}
+	if doc.Profiles == nil {
+		doc.Profiles = make(map[string]profileEntry)
+	}
+	doc.Profiles[profile] = profileEntry{
+		Username:  creds.Username,
+		Password:  creds.Password,
+		ServerURL: creds.ServerURL,
+	}
+	if doc.Default == "" {
+		doc.Default = profile
+	}
+
+	willEncrypt := encrypt || wasEncrypted
+	if willEncrypt && !wasEncrypted {
+		if err := zeroPlaintextCredentialsFile(); err != nil {
+			return err
+		}
+	}
+	if err := writeDocument(doc, willEncrypt); err != nil {
+		return err
+	}
+	return recordProfileIndex(profile, creds.ServerURL)
+}
+
+func loadFromFile(profile string) (Credentials, error) {
+	doc, _, err := loadDocument()
+	if err != nil {
+		return Credentials{}, err
+	}
+	entry, ok := doc.Profiles[profile]
+	if !ok {
+		return Credentials{}, ErrCredentialsNotFound
+	}
+	if strings.TrimSpace(entry.Username) == "" || entry.Password == "" {
+		return Credentials{}, fmt.Errorf("credentials file is missing required fields for profile %q", profile)
+	}
+	if err := ensureProfileIndexed(profile, entry.ServerURL); err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{Username: entry.Username, Password: entry.Password, ServerURL: entry.ServerURL}, nil
+}
+
+func deleteFromFile(profile string) error {
+	doc, wasEncrypted, err := loadDocument()
+	if err != nil {
+		return err
+	}
+	if _, ok := doc.Profiles[profile]; ok {
+		delete(doc.Profiles, profile)
+		if doc.Default == profile {
+			doc.Default = ""
+		}
+		if len(doc.Profiles) == 0 {
+			if err := removeCredentialsFile(); err != nil {
+				return err
+			}
+		} else if err := writeDocument(doc, wasEncrypted); err != nil {
+			return err
+		}
+	}
+	return removeProfileIndex(profile)
+}
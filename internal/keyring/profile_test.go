@@ -0,0 +1,126 @@
+package keyring
+
+import "testing"
+
+func TestProfileRoundTripAndIsolation(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(ProfileEnvVar, "")
+
+	staging := Credentials{Username: "staging@example.com", Password: "staging-secret", ServerURL: "https://staging.magnit.example"}
+	prod := Credentials{Username: "prod@example.com", Password: "prod-secret", ServerURL: "https://vms.magnit.example"}
+
+	if err := SaveCredentialsForProfile("staging", staging, StoreFile); err != nil {
+		t.Fatalf("save staging profile: %v", err)
+	}
+	if err := SaveCredentialsForProfile("prod", prod, StoreFile); err != nil {
+		t.Fatalf("save prod profile: %v", err)
+	}
+
+	got, err := LoadCredentialsForProfile("staging", StoreFile)
+	if err != nil {
+		t.Fatalf("load staging profile: %v", err)
+	}
+	if got != staging {
+		t.Fatalf("unexpected staging credentials: got=%+v want=%+v", got, staging)
+	}
+
+	got, err = LoadCredentialsForProfile("prod", StoreFile)
+	if err != nil {
+		t.Fatalf("load prod profile: %v", err)
+	}
+	if got != prod {
+		t.Fatalf("unexpected prod credentials: got=%+v want=%+v", got, prod)
+	}
+
+	profiles, err := ListProfiles()
+	if err != nil {
+		t.Fatalf("list profiles: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0] != "prod" || profiles[1] != "staging" {
+		t.Fatalf("unexpected profile list: %v", profiles)
+	}
+
+	if err := DeleteCredentialsForProfile("staging", StoreFile); err != nil {
+		t.Fatalf("delete staging profile: %v", err)
+	}
+	if _, err := LoadCredentialsForProfile("staging", StoreFile); err != ErrCredentialsNotFound {
+		t.Fatalf("expected ErrCredentialsNotFound after delete, got %v", err)
+	}
+	if _, err := LoadCredentialsForProfile("prod", StoreFile); err != nil {
+		t.Fatalf("prod profile should survive staging delete: %v", err)
+	}
+}
+
+func TestDefaultProfileBackwardCompatibility(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(ProfileEnvVar, "")
+
+	want := Credentials{Username: "user@example.com", Password: "secret"}
+	if err := SaveCredentialsWithStore(want, StoreFile); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+
+	got, err := LoadCredentialsForProfile("default", StoreFile)
+	if err != nil {
+		t.Fatalf("load default profile: %v", err)
+	}
+	if got != want {
+		t.Fatalf("unexpected credentials: got=%+v want=%+v", got, want)
+	}
+
+	got, err = LoadCredentials()
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+	if got != want {
+		t.Fatalf("unexpected credentials via bare LoadCredentials: got=%+v want=%+v", got, want)
+	}
+}
+
+func TestSetDefaultProfile(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(ProfileEnvVar, "")
+
+	partner := Credentials{Username: "partner@example.com", Password: "partner-secret"}
+	if err := SaveCredentialsForProfile("partner", partner, StoreFile); err != nil {
+		t.Fatalf("save partner profile: %v", err)
+	}
+	if err := SetDefaultProfile("partner"); err != nil {
+		t.Fatalf("set default profile: %v", err)
+	}
+
+	got, err := LoadCredentials()
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+	if got != partner {
+		t.Fatalf("expected default profile to resolve to partner: got=%+v want=%+v", got, partner)
+	}
+}
+
+func TestProfileEnvVarOverridesExplicitProfile(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+
+	want := Credentials{Username: "env-profile@example.com", Password: "env-secret"}
+	t.Setenv(ProfileEnvVar, "fromenv")
+	if err := SaveCredentialsForProfile("ignored", want, StoreFile); err != nil {
+		t.Fatalf("save credentials: %v", err)
+	}
+
+	got, err := LoadCredentialsForProfile("ignored", StoreFile)
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+	if got != want {
+		t.Fatalf("unexpected credentials: got=%+v want=%+v", got, want)
+	}
+
+	t.Setenv(ProfileEnvVar, "")
+	if _, err := LoadCredentialsForProfile("ignored", StoreFile); err != ErrCredentialsNotFound {
+		t.Fatalf("expected the 'ignored' profile name to never have been used, got %v", err)
+	}
+}
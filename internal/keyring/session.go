@@ -0,0 +1,327 @@
+package keyring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ihildy/magnit-vms-cli/internal/config"
+)
+
+const sessionKeySuffix = "session"
+
+// SaveSession persists an opaque, caller-defined session blob (typically a
+// JSON-encoded cookie jar snapshot) for the default profile, using the
+// same store-selection logic as SaveCredentials.
+func SaveSession(data []byte) error {
+	return SaveSessionForProfile("", data, "")
+}
+
+// SaveSessionWithStore is SaveSession with an explicit preferred store,
+// mirroring SaveCredentialsWithStore.
+func SaveSessionWithStore(data []byte, preferredStore string) error {
+	return SaveSessionForProfile("", data, preferredStore)
+}
+
+// SaveSessionForProfile persists data as the session for profileName,
+// resolved the same way SaveCredentialsForProfile resolves its profile.
+func SaveSessionForProfile(profileName string, data []byte, preferredStore string) error {
+	if len(data) == 0 {
+		return errors.New("session data is required")
+	}
+
+	profile, err := resolveProfile(profileName)
+	if err != nil {
+		return err
+	}
+
+	store, err := resolveStore(preferredStore)
+	if err != nil {
+		return err
+	}
+
+	if isHelperStore(store) {
+		name, ok := resolveHelperName(store)
+		if !ok {
+			return fmt.Errorf("credential helper name not configured (set %s or use %s:<name>)", CredentialHelperEnvVar, StoreHelper)
+		}
+		return saveSessionToHelper(name, profile, data)
+	}
+
+	switch store {
+	case StoreKeyring:
+		return saveSessionToKeyring(profile, data)
+	case StoreFile:
+		return saveSessionToFile(profile, data, false)
+	case StoreFileEncrypted:
+		return saveSessionToFile(profile, data, true)
+	case StoreAuto:
+		keyringErr := saveSessionToKeyring(profile, data)
+		if keyringErr == nil {
+			return nil
+		}
+		if name, ok := resolveHelperName(""); ok {
+			if helperErr := saveSessionToHelper(name, profile, data); helperErr == nil {
+				return nil
+			}
+		}
+		if fileErr := saveSessionToFile(profile, data, encryptionConfigured()); fileErr != nil {
+			return fmt.Errorf("save session failed (keyring: %v, file: %w)", keyringErr, fileErr)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported credential store %q", store)
+	}
+}
+
+// LoadSession loads the session for the default profile.
+func LoadSession() ([]byte, error) {
+	return LoadSessionForProfile("", "")
+}
+
+// LoadSessionWithStore is LoadSession with an explicit preferred store.
+func LoadSessionWithStore(preferredStore string) ([]byte, error) {
+	return LoadSessionForProfile("", preferredStore)
+}
+
+// LoadSessionForProfile loads the session previously saved for profileName.
+// It returns ErrCredentialsNotFound if none was saved.
+func LoadSessionForProfile(profileName string, preferredStore string) ([]byte, error) {
+	profile, err := resolveProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := resolveStore(preferredStore)
+	if err != nil {
+		return nil, err
+	}
+
+	if isHelperStore(store) {
+		name, ok := resolveHelperName(store)
+		if !ok {
+			return nil, fmt.Errorf("credential helper name not configured (set %s or use %s:<name>)", CredentialHelperEnvVar, StoreHelper)
+		}
+		return loadSessionFromHelper(name, profile)
+	}
+
+	switch store {
+	case StoreKeyring:
+		return loadSessionFromKeyring(profile)
+	case StoreFile, StoreFileEncrypted:
+		return loadSessionFromFile(profile)
+	case StoreAuto:
+		data, err := loadSessionFromKeyring(profile)
+		if err == nil {
+			return data, nil
+		}
+		var helperErr error
+		if name, ok := resolveHelperName(""); ok {
+			var helperData []byte
+			if helperData, helperErr = loadSessionFromHelper(name, profile); helperErr == nil {
+				return helperData, nil
+			}
+		}
+		fileData, fileErr := loadSessionFromFile(profile)
+		if fileErr == nil {
+			return fileData, nil
+		}
+		if notFoundOrNil(err) && notFoundOrNil(helperErr) && notFoundOrNil(fileErr) {
+			return nil, ErrCredentialsNotFound
+		}
+		return nil, fmt.Errorf("load session failed (keyring: %v, helper: %v, file: %w)", err, helperErr, fileErr)
+	default:
+		return nil, fmt.Errorf("unsupported credential store %q", store)
+	}
+}
+
+// DeleteSession removes the session for the default profile.
+func DeleteSession() error {
+	return DeleteSessionForProfile("", "")
+}
+
+// DeleteSessionWithStore is DeleteSession with an explicit preferred store.
+func DeleteSessionWithStore(preferredStore string) error {
+	return DeleteSessionForProfile("", preferredStore)
+}
+
+// DeleteSessionForProfile removes the session saved for profileName, if any.
+func DeleteSessionForProfile(profileName string, preferredStore string) error {
+	profile, err := resolveProfile(profileName)
+	if err != nil {
+		return err
+	}
+
+	store, err := resolveStore(preferredStore)
+	if err != nil {
+		return err
+	}
+
+	if isHelperStore(store) {
+		name, ok := resolveHelperName(store)
+		if !ok {
+			return fmt.Errorf("credential helper name not configured (set %s or use %s:<name>)", CredentialHelperEnvVar, StoreHelper)
+		}
+		return deleteSessionFromHelper(name, profile)
+	}
+
+	switch store {
+	case StoreKeyring:
+		return deleteSessionFromKeyring(profile)
+	case StoreFile, StoreFileEncrypted:
+		return deleteSessionFromFile(profile)
+	case StoreAuto:
+		keyringErr := deleteSessionFromKeyring(profile)
+		var helperErr error
+		if name, ok := resolveHelperName(""); ok {
+			helperErr = deleteSessionFromHelper(name, profile)
+		}
+		fileErr := deleteSessionFromFile(profile)
+		if keyringErr != nil && helperErr != nil && fileErr != nil {
+			return fmt.Errorf("delete session failed (keyring: %v, helper: %v, file: %w)", keyringErr, helperErr, fileErr)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported credential store %q", store)
+	}
+}
+
+func sessionKeyringKey(profile string) string {
+	if profile == defaultProfileName {
+		return sessionKeySuffix
+	}
+	return profile + "." + sessionKeySuffix
+}
+
+func saveSessionToKeyring(profile string, data []byte) error {
+	if err := zk.Set(serviceName, sessionKeyringKey(profile), string(data)); err != nil {
+		return fmt.Errorf("save session to keyring: %w", err)
+	}
+	return nil
+}
+
+func loadSessionFromKeyring(profile string) ([]byte, error) {
+	data, err := zk.Get(serviceName, sessionKeyringKey(profile))
+	if err != nil {
+		if errors.Is(err, zk.ErrNotFound) {
+			return nil, ErrCredentialsNotFound
+		}
+		return nil, fmt.Errorf("read session from keyring: %w", err)
+	}
+	return []byte(data), nil
+}
+
+func deleteSessionFromKeyring(profile string) error {
+	if err := zk.Delete(serviceName, sessionKeyringKey(profile)); err != nil && !errors.Is(err, zk.ErrNotFound) {
+		return fmt.Errorf("delete session from keyring: %w", err)
+	}
+	return nil
+}
+
+func sessionFileName(profile string) string {
+	return fmt.Sprintf("session-%s.json", profile)
+}
+
+func sessionFilePath(profile string) (string, error) {
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), sessionFileName(profile)), nil
+}
+
+// saveSessionToFile writes data to the session file for profile, encrypting
+// it first when encrypt is true, mirroring saveToFile's credentials.yaml
+// handling.
+func saveSessionToFile(profile string, data []byte, encrypt bool) error {
+	path, err := sessionFilePath(profile)
+	if err != nil {
+		return fmt.Errorf("resolve session path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create session dir: %w", err)
+	}
+	out := data
+	if encrypt {
+		encrypted, err := encryptPlaintext(data)
+		if err != nil {
+			return err
+		}
+		out = encrypted
+	}
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return fmt.Errorf("write session file: %w", err)
+	}
+	return nil
+}
+
+// loadSessionFromFile reads the session file for profile, transparently
+// decrypting it first if it was written by the encrypted-file backend,
+// mirroring loadDocument's credentials.yaml handling.
+func loadSessionFromFile(profile string) ([]byte, error) {
+	path, err := sessionFilePath(profile)
+	if err != nil {
+		return nil, fmt.Errorf("resolve session path: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrCredentialsNotFound
+		}
+		return nil, fmt.Errorf("read session file: %w", err)
+	}
+	if isEncryptedDocument(data) {
+		return decryptPlaintext(data)
+	}
+	return data, nil
+}
+
+func deleteSessionFromFile(profile string) error {
+	path, err := sessionFilePath(profile)
+	if err != nil {
+		return fmt.Errorf("resolve session path: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete session file: %w", err)
+	}
+	return nil
+}
+
+func sessionServerURL(profile string) string {
+	return helperServerURL(profile, "") + "/session"
+}
+
+func saveSessionToHelper(name, profile string, data []byte) error {
+	payload, err := json.Marshal(helperStorePayload{ServerURL: sessionServerURL(profile), Username: sessionKeySuffix, Secret: string(data)})
+	if err != nil {
+		return fmt.Errorf("marshal helper session payload: %w", err)
+	}
+	if _, err := runCredentialHelper(name, "store", payload); err != nil {
+		return fmt.Errorf("save session to helper %q: %w", name, err)
+	}
+	return nil
+}
+
+func loadSessionFromHelper(name, profile string) ([]byte, error) {
+	out, err := runCredentialHelper(name, "get", []byte(sessionServerURL(profile)))
+	if err != nil {
+		if errors.Is(err, ErrCredentialsNotFound) {
+			return nil, ErrCredentialsNotFound
+		}
+		return nil, fmt.Errorf("load session from helper %q: %w", name, err)
+	}
+	var resp helperGetResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("parse helper get response: %w", err)
+	}
+	return []byte(resp.Secret), nil
+}
+
+func deleteSessionFromHelper(name, profile string) error {
+	if _, err := runCredentialHelper(name, "erase", []byte(sessionServerURL(profile))); err != nil && !errors.Is(err, ErrCredentialsNotFound) {
+		return fmt.Errorf("delete session from helper %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package keyring
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSessionFileRoundTrip(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(ProfileEnvVar, "")
+
+	want := []byte(`{"base_url":"https://example.com","cookies":[{"name":"productionaccess_token","value":"abc123"}]}`)
+	if err := SaveSessionWithStore(want, StoreFile); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	got, err := LoadSessionWithStore(StoreFile)
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("unexpected session: got=%s want=%s", got, want)
+	}
+
+	if err := DeleteSessionWithStore(StoreFile); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if _, err := LoadSessionWithStore(StoreFile); err != ErrCredentialsNotFound {
+		t.Fatalf("expected ErrCredentialsNotFound after delete, got %v", err)
+	}
+}
+
+func TestSessionEncryptedFileRoundTrip(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(ProfileEnvVar, "")
+	t.Setenv(PassphraseEnvVar, "")
+	t.Setenv(AgeRecipientEnvVar, "")
+	stubPassphrasePrompt(t, "correct horse battery staple")
+
+	want := []byte(`{"base_url":"https://example.com","cookies":[{"name":"productionaccess_token","value":"abc123"}]}`)
+	if err := SaveSessionWithStore(want, StoreFileEncrypted); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	path, err := sessionFilePath(defaultProfileName)
+	if err != nil {
+		t.Fatalf("session path: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read session file: %v", err)
+	}
+	if !isEncryptedDocument(data) {
+		t.Fatalf("expected session file to be encrypted, got %q", data)
+	}
+
+	got, err := LoadSessionWithStore(StoreFileEncrypted)
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("unexpected session: got=%s want=%s", got, want)
+	}
+
+	if err := DeleteSessionWithStore(StoreFileEncrypted); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if _, err := LoadSessionWithStore(StoreFileEncrypted); err != ErrCredentialsNotFound {
+		t.Fatalf("expected ErrCredentialsNotFound after delete, got %v", err)
+	}
+}
+
+func TestSessionIsolatedPerProfile(t *testing.T) {
+	isolateConfigHome(t)
+	t.Setenv(CredentialStoreEnvVar, "")
+	t.Setenv(ProfileEnvVar, "")
+
+	staging := []byte(`{"base_url":"https://staging.example.com"}`)
+	prod := []byte(`{"base_url":"https://vms.example.com"}`)
+
+	if err := SaveSessionForProfile("staging", staging, StoreFile); err != nil {
+		t.Fatalf("save staging session: %v", err)
+	}
+	if err := SaveSessionForProfile("prod", prod, StoreFile); err != nil {
+		t.Fatalf("save prod session: %v", err)
+	}
+
+	got, err := LoadSessionForProfile("staging", StoreFile)
+	if err != nil {
+		t.Fatalf("load staging session: %v", err)
+	}
+	if string(got) != string(staging) {
+		t.Fatalf("unexpected staging session: got=%s want=%s", got, staging)
+	}
+
+	got, err = LoadSessionForProfile("prod", StoreFile)
+	if err != nil {
+		t.Fatalf("load prod session: %v", err)
+	}
+	if string(got) != string(prod) {
+		t.Fatalf("unexpected prod session: got=%s want=%s", got, prod)
+	}
+}
@@ -24,9 +24,14 @@ const (
 	defaultCredentialBackend = StoreAuto
 )
 
+// StoreFileEncrypted, PassphraseEnvVar, AgeRecipientEnvVar and
+// AgeIdentityEnvVar are declared in encrypted_file.go, alongside the rest
+// of the file-store encryption support.
+
 type Credentials struct {
-	Username string
-	Password string
+	Username  string
+	Password  string
+	ServerURL string
 }
 
 var ErrCredentialsNotFound = errors.New("credentials not found")
@@ -36,6 +41,14 @@ func SaveCredentials(creds Credentials) error {
 }
 
 func SaveCredentialsWithStore(creds Credentials, preferredStore string) error {
+	return SaveCredentialsForProfile("", creds, preferredStore)
+}
+
+// SaveCredentialsForProfile saves creds under the named profile. An empty
+// profileName resolves via ProfileEnvVar, the configured default profile,
+// then finally defaultProfileName, the same precedence LoadCredentialsForProfile
+// and DeleteCredentialsForProfile use.
+func SaveCredentialsForProfile(profileName string, creds Credentials, preferredStore string) error {
 	if creds.Username == "" {
 		return errors.New("username is required")
 	}
@@ -43,22 +56,42 @@ func SaveCredentialsWithStore(creds Credentials, preferredStore string) error {
 		return errors.New("password is required")
 	}
 
+	profile, err := resolveProfile(profileName)
+	if err != nil {
+		return err
+	}
+
 	store, err := resolveStore(preferredStore)
 	if err != nil {
 		return err
 	}
 
+	if isHelperStore(store) {
+		name, ok := resolveHelperName(store)
+		if !ok {
+			return fmt.Errorf("credential helper name not configured (set %s or use %s:<name>)", CredentialHelperEnvVar, StoreHelper)
+		}
+		return saveToHelper(name, profile, creds)
+	}
+
 	switch store {
 	case StoreKeyring:
-		return saveToKeyring(creds)
+		return saveToKeyring(profile, creds)
 	case StoreFile:
-		return saveToFile(creds)
+		return saveToFile(profile, creds, false)
+	case StoreFileEncrypted:
+		return saveToFile(profile, creds, true)
 	case StoreAuto:
-		keyringErr := saveToKeyring(creds)
+		keyringErr := saveToKeyring(profile, creds)
 		if keyringErr == nil {
 			return nil
 		}
-		if fileErr := saveToFile(creds); fileErr != nil {
+		if name, ok := resolveHelperName(""); ok {
+			if helperErr := saveToHelper(name, profile, creds); helperErr == nil {
+				return nil
+			}
+		}
+		if fileErr := saveToFile(profile, creds, encryptionConfigured()); fileErr != nil {
 			return fmt.Errorf("save credentials failed (keyring: %v, file: %w)", keyringErr, fileErr)
 		}
 		return nil
@@ -72,60 +105,106 @@ func LoadCredentials() (Credentials, error) {
 }
 
 func LoadCredentialsWithStore(preferredStore string) (Credentials, error) {
+	return LoadCredentialsForProfile("", preferredStore)
+}
+
+func LoadCredentialsForProfile(profileName string, preferredStore string) (Credentials, error) {
+	profile, err := resolveProfile(profileName)
+	if err != nil {
+		return Credentials{}, err
+	}
+
 	store, err := resolveStore(preferredStore)
 	if err != nil {
 		return Credentials{}, err
 	}
 
+	if isHelperStore(store) {
+		name, ok := resolveHelperName(store)
+		if !ok {
+			return Credentials{}, fmt.Errorf("credential helper name not configured (set %s or use %s:<name>)", CredentialHelperEnvVar, StoreHelper)
+		}
+		return loadFromHelper(name, profile)
+	}
+
 	switch store {
 	case StoreKeyring:
-		return loadFromKeyring()
-	case StoreFile:
-		return loadFromFile()
+		return loadFromKeyring(profile)
+	case StoreFile, StoreFileEncrypted:
+		return loadFromFile(profile)
 	case StoreAuto:
-		creds, err := loadFromKeyring()
+		creds, err := loadFromKeyring(profile)
 		if err == nil {
 			return creds, nil
 		}
-		fileCreds, fileErr := loadFromFile()
+		var helperErr error
+		if name, ok := resolveHelperName(""); ok {
+			var helperCreds Credentials
+			if helperCreds, helperErr = loadFromHelper(name, profile); helperErr == nil {
+				return helperCreds, nil
+			}
+		}
+		fileCreds, fileErr := loadFromFile(profile)
 		if fileErr == nil {
 			return fileCreds, nil
 		}
-		if errors.Is(err, ErrCredentialsNotFound) && errors.Is(fileErr, ErrCredentialsNotFound) {
+		if notFoundOrNil(err) && notFoundOrNil(helperErr) && notFoundOrNil(fileErr) {
 			return Credentials{}, ErrCredentialsNotFound
 		}
-		if errors.Is(err, ErrCredentialsNotFound) {
-			return Credentials{}, fileErr
-		}
-		if errors.Is(fileErr, ErrCredentialsNotFound) {
-			return Credentials{}, err
-		}
-		return Credentials{}, fmt.Errorf("load credentials failed (keyring: %v, file: %w)", err, fileErr)
+		return Credentials{}, fmt.Errorf("load credentials failed (keyring: %v, helper: %v, file: %w)", err, helperErr, fileErr)
 	default:
 		return Credentials{}, fmt.Errorf("unsupported credential store %q", store)
 	}
 }
 
+// notFoundOrNil reports whether err is nil or ErrCredentialsNotFound, i.e.
+// whether it should be treated as "nothing stored here" rather than a real
+// failure when combining results across Auto's fallback chain.
+func notFoundOrNil(err error) bool {
+	return err == nil || errors.Is(err, ErrCredentialsNotFound)
+}
+
 func DeleteCredentials() error {
 	return DeleteCredentialsWithStore("")
 }
 
 func DeleteCredentialsWithStore(preferredStore string) error {
+	return DeleteCredentialsForProfile("", preferredStore)
+}
+
+func DeleteCredentialsForProfile(profileName string, preferredStore string) error {
+	profile, err := resolveProfile(profileName)
+	if err != nil {
+		return err
+	}
+
 	store, err := resolveStore(preferredStore)
 	if err != nil {
 		return err
 	}
 
+	if isHelperStore(store) {
+		name, ok := resolveHelperName(store)
+		if !ok {
+			return fmt.Errorf("credential helper name not configured (set %s or use %s:<name>)", CredentialHelperEnvVar, StoreHelper)
+		}
+		return deleteFromHelper(name, profile)
+	}
+
 	switch store {
 	case StoreKeyring:
-		return deleteFromKeyring()
-	case StoreFile:
-		return deleteFromFile()
+		return deleteFromKeyring(profile)
+	case StoreFile, StoreFileEncrypted:
+		return deleteFromFile(profile)
 	case StoreAuto:
-		keyringErr := deleteFromKeyring()
-		fileErr := deleteFromFile()
-		if keyringErr != nil && fileErr != nil {
-			return fmt.Errorf("delete credentials failed (keyring: %v, file: %w)", keyringErr, fileErr)
+		keyringErr := deleteFromKeyring(profile)
+		var helperErr error
+		if name, ok := resolveHelperName(""); ok {
+			helperErr = deleteFromHelper(name, profile)
+		}
+		fileErr := deleteFromFile(profile)
+		if keyringErr != nil && helperErr != nil && fileErr != nil {
+			return fmt.Errorf("delete credentials failed (keyring: %v, helper: %v, file: %w)", keyringErr, helperErr, fileErr)
 		}
 		return nil
 	default:
@@ -134,30 +213,33 @@ func DeleteCredentialsWithStore(preferredStore string) error {
 }
 
 func ValidateCredentialStore(store string) error {
-	switch normalizeStore(store) {
-	case StoreAuto, StoreKeyring, StoreFile:
+	normalized := normalizeStore(store)
+	switch normalized {
+	case StoreAuto, StoreKeyring, StoreFile, StoreFileEncrypted, StoreHelper:
 		return nil
-	default:
-		return fmt.Errorf("invalid credential store %q (allowed: %s, %s, %s)", store, StoreAuto, StoreKeyring, StoreFile)
 	}
+	if strings.HasPrefix(normalized, StoreHelper+":") && normalized != StoreHelper+":" {
+		return nil
+	}
+	return fmt.Errorf("invalid credential store %q (allowed: %s, %s, %s, %s, %s, %s:<name>)", store, StoreAuto, StoreKeyring, StoreFile, StoreFileEncrypted, StoreHelper, StoreHelper)
 }
 
 func NormalizeCredentialStore(store string) string {
 	return normalizeStore(store)
 }
 
-func saveToKeyring(creds Credentials) error {
-	if err := zk.Set(serviceName, userKey, creds.Username); err != nil {
+func saveToKeyring(profile string, creds Credentials) error {
+	if err := zk.Set(serviceName, profileUserKey(profile), creds.Username); err != nil {
 		return fmt.Errorf("save username to keyring: %w", err)
 	}
-	if err := zk.Set(serviceName, passKey, creds.Password); err != nil {
+	if err := zk.Set(serviceName, profilePassKey(profile), creds.Password); err != nil {
 		return fmt.Errorf("save password to keyring: %w", err)
 	}
-	return nil
+	return recordProfileIndex(profile, creds.ServerURL)
 }
 
-func loadFromKeyring() (Credentials, error) {
-	username, err := zk.Get(serviceName, userKey)
+func loadFromKeyring(profile string) (Credentials, error) {
+	username, err := zk.Get(serviceName, profileUserKey(profile))
 	if err != nil {
 		if errors.Is(err, zk.ErrNotFound) {
 			return Credentials{}, ErrCredentialsNotFound
@@ -165,7 +247,7 @@ func loadFromKeyring() (Credentials, error) {
 		return Credentials{}, fmt.Errorf("read username from keyring: %w", err)
 	}
 
-	password, err := zk.Get(serviceName, passKey)
+	password, err := zk.Get(serviceName, profilePassKey(profile))
 	if err != nil {
 		if errors.Is(err, zk.ErrNotFound) {
 			return Credentials{}, ErrCredentialsNotFound
@@ -173,19 +255,19 @@ func loadFromKeyring() (Credentials, error) {
 		return Credentials{}, fmt.Errorf("read password from keyring: %w", err)
 	}
 
-	return Credentials{Username: username, Password: password}, nil
+	return Credentials{Username: username, Password: password, ServerURL: profileServerURL(profile)}, nil
 }
 
-func deleteFromKeyring() error {
-	userErr := zk.Delete(serviceName, userKey)
-	passErr := zk.Delete(serviceName, passKey)
+func deleteFromKeyring(profile string) error {
+	userErr := zk.Delete(serviceName, profileUserKey(profile))
+	passErr := zk.Delete(serviceName, profilePassKey(profile))
 	if userErr != nil && !errors.Is(userErr, zk.ErrNotFound) {
 		return fmt.Errorf("delete username from keyring: %w", userErr)
 	}
 	if passErr != nil && !errors.Is(passErr, zk.ErrNotFound) {
 		return fmt.Errorf("delete password from keyring: %w", passErr)
 	}
-	return nil
+	return removeProfileIndex(profile)
 }
 
 func credentialsFilePath() (string, error) {
@@ -196,47 +278,74 @@ func credentialsFilePath() (string, error) {
 	return filepath.Join(filepath.Dir(cfgPath), credentialsFileName), nil
 }
 
-func saveToFile(creds Credentials) error {
+// loadDocument reads credentials.yaml as a credentialsDocument, transparently
+// decrypting it first if it was written by the encrypted-file backend. A
+// missing file is not an error: it simply means no profiles have been
+// recorded yet. The returned bool reports whether the file was encrypted on
+// disk, so callers that write the document back can preserve that.
+func loadDocument() (credentialsDocument, bool, error) {
 	path, err := credentialsFilePath()
 	if err != nil {
-		return fmt.Errorf("resolve credentials path: %w", err)
+		return credentialsDocument{}, false, fmt.Errorf("resolve credentials path: %w", err)
 	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
-		return fmt.Errorf("create credentials dir: %w", err)
-	}
-	data, err := yaml.Marshal(&creds)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("marshal credentials: %w", err)
+		if errors.Is(err, os.ErrNotExist) {
+			return credentialsDocument{}, false, nil
+		}
+		return credentialsDocument{}, false, fmt.Errorf("read credentials file: %w", err)
 	}
-	if err := os.WriteFile(path, data, 0o600); err != nil {
-		return fmt.Errorf("write credentials file: %w", err)
+
+	if isEncryptedDocument(data) {
+		doc, err := decryptDocument(data)
+		return doc, true, err
 	}
-	return nil
+
+	var doc credentialsDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return credentialsDocument{}, false, fmt.Errorf("parse credentials file: %w", err)
+	}
+	if len(doc.Profiles) == 0 {
+		// Pre-profile credentials.yaml held a flat Username/Password pair at
+		// the document root; treat that as the default profile so existing
+		// file-store users aren't forced to re-login after upgrading.
+		var legacy Credentials
+		if err := yaml.Unmarshal(data, &legacy); err == nil && legacy.Username != "" {
+			doc.Profiles = map[string]profileEntry{
+				defaultProfileName: {Username: legacy.Username, Password: legacy.Password, ServerURL: legacy.ServerURL},
+			}
+			doc.Default = defaultProfileName
+		}
+	}
+	return doc, false, nil
 }
 
-func loadFromFile() (Credentials, error) {
+// writeDocument persists doc to credentials.yaml, encrypting it first when
+// encrypt is true.
+func writeDocument(doc credentialsDocument, encrypt bool) error {
 	path, err := credentialsFilePath()
 	if err != nil {
-		return Credentials{}, fmt.Errorf("resolve credentials path: %w", err)
+		return fmt.Errorf("resolve credentials path: %w", err)
 	}
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return Credentials{}, ErrCredentialsNotFound
-		}
-		return Credentials{}, fmt.Errorf("read credentials file: %w", err)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create credentials dir: %w", err)
 	}
-	var creds Credentials
-	if err := yaml.Unmarshal(data, &creds); err != nil {
-		return Credentials{}, fmt.Errorf("parse credentials file: %w", err)
+	var data []byte
+	if encrypt {
+		data, err = encryptDocument(doc)
+	} else {
+		data, err = yaml.Marshal(&doc)
 	}
-	if strings.TrimSpace(creds.Username) == "" || creds.Password == "" {
-		return Credentials{}, fmt.Errorf("credentials file is missing required fields")
+	if err != nil {
+		return fmt.Errorf("marshal credentials: %w", err)
 	}
-	return creds, nil
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write credentials file: %w", err)
+	}
+	return nil
 }
 
-func deleteFromFile() error {
+func removeCredentialsFile() error {
 	path, err := credentialsFilePath()
 	if err != nil {
 		return fmt.Errorf("resolve credentials path: %w", err)
@@ -262,10 +371,29 @@ func resolveStore(preferredStore string) (string, error) {
 	return store, nil
 }
 
+// normalizeStore lowercases store the way every other store keyword is
+// matched case-insensitively, except for a "helper:<name>" selector: the
+// name portion is exec'd as part of a magnit-credential-<name> binary
+// name (see resolveHelperName), so it keeps whatever case the caller gave
+// it instead of silently resolving to a different binary.
 func normalizeStore(store string) string {
-	value := strings.ToLower(strings.TrimSpace(store))
+	value := strings.TrimSpace(store)
 	if value == "" {
 		return defaultCredentialBackend
 	}
-	return value
+	if name, ok := cutHelperPrefix(value); ok {
+		return StoreHelper + ":" + name
+	}
+	return strings.ToLower(value)
+}
+
+// cutHelperPrefix reports whether value is a "helper:<name>" store
+// selector, matching "helper" case-insensitively but returning name with
+// its original casing intact.
+func cutHelperPrefix(value string) (string, bool) {
+	prefix := StoreHelper + ":"
+	if len(value) <= len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return "", false
+	}
+	return value[len(prefix):], true
 }